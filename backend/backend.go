@@ -0,0 +1,11 @@
+package main
+
+import "context"
+
+// ClaudeBackend abstracts how a chat message is actually turned into a
+// ClaudeResponse. Swapping implementations lets the server trade the
+// per-request cold start of exec.Command for a warm worker pool or a direct
+// HTTP call, without touching chatHandler.
+type ClaudeBackend interface {
+    Send(ctx context.Context, req ChatRequest) (*ClaudeResponse, error)
+}
@@ -0,0 +1,93 @@
+package main
+
+import (
+    "encoding/json"
+    "fmt"
+
+    bolt "go.etcd.io/bbolt"
+)
+
+var sessionsBucket = []byte("sessions")
+
+// boltSessionStore is a SessionStore backed by a bbolt file, so sessions
+// (and the --resume IDs the frontend keeps reusing) survive a restart.
+type boltSessionStore struct {
+    db *bolt.DB
+}
+
+func newBoltSessionStore(path string) (*boltSessionStore, error) {
+    db, err := bolt.Open(path, 0600, nil)
+    if err != nil {
+        return nil, fmt.Errorf("open session db: %w", err)
+    }
+
+    err = db.Update(func(tx *bolt.Tx) error {
+        _, err := tx.CreateBucketIfNotExists(sessionsBucket)
+        return err
+    })
+    if err != nil {
+        db.Close()
+        return nil, fmt.Errorf("create sessions bucket: %w", err)
+    }
+
+    return &boltSessionStore{db: db}, nil
+}
+
+func (s *boltSessionStore) Append(sessionID string, rec SessionRecord) error {
+    return s.db.Update(func(tx *bolt.Tx) error {
+        b := tx.Bucket(sessionsBucket)
+
+        sess := &Session{ID: sessionID}
+        if existing := b.Get([]byte(sessionID)); existing != nil {
+            if err := json.Unmarshal(existing, sess); err != nil {
+                return fmt.Errorf("decode session %q: %w", sessionID, err)
+            }
+        }
+
+        sess.Records = append(sess.Records, rec)
+        sess.TotalCost += rec.Cost
+
+        encoded, err := json.Marshal(sess)
+        if err != nil {
+            return err
+        }
+        return b.Put([]byte(sessionID), encoded)
+    })
+}
+
+func (s *boltSessionStore) List() ([]*Session, error) {
+    var sessions []*Session
+    err := s.db.View(func(tx *bolt.Tx) error {
+        return tx.Bucket(sessionsBucket).ForEach(func(k, v []byte) error {
+            sess := &Session{}
+            if err := json.Unmarshal(v, sess); err != nil {
+                return fmt.Errorf("decode session %q: %w", k, err)
+            }
+            sessions = append(sessions, sess)
+            return nil
+        })
+    })
+    return sessions, err
+}
+
+func (s *boltSessionStore) Get(sessionID string) (*Session, error) {
+    var sess *Session
+    err := s.db.View(func(tx *bolt.Tx) error {
+        v := tx.Bucket(sessionsBucket).Get([]byte(sessionID))
+        if v == nil {
+            return fmt.Errorf("session %q not found", sessionID)
+        }
+        sess = &Session{}
+        return json.Unmarshal(v, sess)
+    })
+    if err != nil {
+        return nil, err
+    }
+    return sess, nil
+}
+
+func (s *boltSessionStore) Delete(sessionID string) error {
+    return s.db.Update(func(tx *bolt.Tx) error {
+        return tx.Bucket(sessionsBucket).Delete([]byte(sessionID))
+    })
+}
@@ -0,0 +1,42 @@
+package main
+
+import (
+    "net/http"
+    "time"
+)
+
+// totalCostToday sums CostUSD across every recorded turn in the last 24h,
+// across all sessions, so a MaxDailyCostUSD budget can be enforced globally.
+func totalCostToday(store SessionStore) (float64, error) {
+    sessions, err := store.List()
+    if err != nil {
+        return 0, err
+    }
+
+    cutoff := time.Now().Add(-24 * time.Hour).Unix()
+    var total float64
+    for _, sess := range sessions {
+        for _, rec := range sess.Records {
+            if rec.Timestamp >= cutoff {
+                total += rec.Cost
+            }
+        }
+    }
+    return total, nil
+}
+
+// withCostBudget rejects requests with 402 Payment Required once the last
+// 24h of Claude spend reaches maxDailyCostUSD. A budget of 0 disables the
+// check.
+func withCostBudget(maxDailyCostUSD float64, next http.HandlerFunc) http.HandlerFunc {
+    return func(w http.ResponseWriter, r *http.Request) {
+        if maxDailyCostUSD > 0 {
+            spent, err := totalCostToday(sessionStore)
+            if err == nil && spent >= maxDailyCostUSD {
+                http.Error(w, "Daily Claude cost budget exceeded", http.StatusPaymentRequired)
+                return
+            }
+        }
+        next(w, r)
+    }
+}
@@ -0,0 +1,107 @@
+package main
+
+import (
+    "net/http"
+    "os"
+    "strconv"
+    "strings"
+)
+
+// CORSConfig controls which origins, methods and headers cross-origin
+// requests are allowed to use. It replaces the old hard-coded
+// Access-Control-Allow-Origin: * with a configurable allow-list.
+type CORSConfig struct {
+    AllowedOrigins   []string
+    AllowedMethods   []string
+    AllowedHeaders   []string
+    AllowCredentials bool
+    MaxAge           int // seconds, sent as Access-Control-Max-Age
+}
+
+// corsConfigFromEnv builds a CORSConfig from environment variables,
+// falling back to sane single-origin defaults for local development:
+//
+//	CORS_ALLOWED_ORIGINS   comma-separated list, e.g. "https://example.com,https://app.example.com"
+//	CORS_ALLOWED_METHODS   comma-separated list, default "GET,POST,DELETE,OPTIONS"
+//	CORS_ALLOWED_HEADERS   comma-separated list, default "Content-Type"
+//	CORS_ALLOW_CREDENTIALS "true"/"false", default false
+//	CORS_MAX_AGE           seconds, default 600
+func corsConfigFromEnv() CORSConfig {
+    cfg := CORSConfig{
+        AllowedOrigins:   []string{"http://localhost:3000"},
+        AllowedMethods:   []string{"GET", "POST", "DELETE", "OPTIONS"},
+        AllowedHeaders:   []string{"Content-Type"},
+        AllowCredentials: false,
+        MaxAge:           600,
+    }
+
+    if v := os.Getenv("CORS_ALLOWED_ORIGINS"); v != "" {
+        cfg.AllowedOrigins = splitAndTrim(v)
+    }
+    if v := os.Getenv("CORS_ALLOWED_METHODS"); v != "" {
+        cfg.AllowedMethods = splitAndTrim(v)
+    }
+    if v := os.Getenv("CORS_ALLOWED_HEADERS"); v != "" {
+        cfg.AllowedHeaders = splitAndTrim(v)
+    }
+    if v := os.Getenv("CORS_ALLOW_CREDENTIALS"); v != "" {
+        cfg.AllowCredentials = v == "true"
+    }
+    if v := os.Getenv("CORS_MAX_AGE"); v != "" {
+        if n, err := strconv.Atoi(v); err == nil {
+            cfg.MaxAge = n
+        }
+    }
+
+    return cfg
+}
+
+func splitAndTrim(v string) []string {
+    parts := strings.Split(v, ",")
+    out := make([]string, 0, len(parts))
+    for _, p := range parts {
+        if p = strings.TrimSpace(p); p != "" {
+            out = append(out, p)
+        }
+    }
+    return out
+}
+
+func (c CORSConfig) originAllowed(origin string) bool {
+    for _, allowed := range c.AllowedOrigins {
+        if allowed == "*" || allowed == origin {
+            return true
+        }
+    }
+    return false
+}
+
+// withCORS wraps next with allow-list CORS handling, echoing back the
+// matched origin instead of "*" and answering OPTIONS preflights directly
+// so the browser can cache them for MaxAge seconds.
+func withCORS(cfg CORSConfig, next http.Handler) http.Handler {
+    allowedMethods := strings.Join(cfg.AllowedMethods, ", ")
+    allowedHeaders := strings.Join(cfg.AllowedHeaders, ", ")
+    maxAge := strconv.Itoa(cfg.MaxAge)
+
+    return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+        origin := r.Header.Get("Origin")
+        if origin != "" && cfg.originAllowed(origin) {
+            w.Header().Set("Access-Control-Allow-Origin", origin)
+            w.Header().Set("Vary", "Origin")
+            if cfg.AllowCredentials {
+                w.Header().Set("Access-Control-Allow-Credentials", "true")
+            }
+        }
+
+        if r.Method == http.MethodOptions {
+            w.Header().Set("Access-Control-Allow-Methods", allowedMethods)
+            w.Header().Set("Access-Control-Allow-Headers", allowedHeaders)
+            w.Header().Set("Access-Control-Max-Age", maxAge)
+            w.WriteHeader(http.StatusNoContent)
+            return
+        }
+
+        next.ServeHTTP(w, r)
+    })
+}
@@ -0,0 +1,165 @@
+package main
+
+import (
+    "bytes"
+    "context"
+    "crypto/rand"
+    "encoding/hex"
+    "encoding/json"
+    "fmt"
+    "net/http"
+    "time"
+)
+
+const anthropicMessagesURL = "https://api.anthropic.com/v1/messages"
+
+// modelCostPerMTok is $/million tokens, input and output, for models this
+// backend knows how to price. Unlisted models fall back to defaultModelCost.
+var modelCostPerMTok = map[string]struct{ input, output float64 }{
+    "claude-sonnet-4-5": {input: 3.0, output: 15.0},
+    "claude-opus-4-1":   {input: 15.0, output: 75.0},
+    "claude-haiku-4-5":  {input: 0.8, output: 4.0},
+}
+
+var defaultModelCost = struct{ input, output float64 }{input: 3.0, output: 15.0}
+
+func estimateCostUSD(model string, inputTokens, outputTokens int) float64 {
+    cost, ok := modelCostPerMTok[model]
+    if !ok {
+        cost = defaultModelCost
+    }
+    return float64(inputTokens)/1_000_000*cost.input + float64(outputTokens)/1_000_000*cost.output
+}
+
+func newSessionID() string {
+    b := make([]byte, 16)
+    if _, err := rand.Read(b); err != nil {
+        return ""
+    }
+    return hex.EncodeToString(b)
+}
+
+// httpBackend talks to the Anthropic Messages API directly, bypassing the
+// claude CLI entirely. It's a ClaudeBackend like Pool, just without a local
+// subprocess in the loop.
+type httpBackend struct {
+    apiKey string
+    model  string
+    client *http.Client
+}
+
+// NewHTTPBackend returns a ClaudeBackend that calls the Anthropic API over
+// HTTP using apiKey, requesting responses from model.
+func NewHTTPBackend(apiKey, model string) *httpBackend {
+    return &httpBackend{
+        apiKey: apiKey,
+        model:  model,
+        client: &http.Client{Timeout: 60 * time.Second},
+    }
+}
+
+type anthropicMessage struct {
+    Role    string `json:"role"`
+    Content string `json:"content"`
+}
+
+type anthropicRequest struct {
+    Model     string              `json:"model"`
+    MaxTokens int                 `json:"max_tokens"`
+    Messages  []anthropicMessage  `json:"messages"`
+}
+
+type anthropicContentBlock struct {
+    Type string `json:"type"`
+    Text string `json:"text"`
+}
+
+type anthropicResponse struct {
+    Content []anthropicContentBlock `json:"content"`
+    Usage   struct {
+        InputTokens  int `json:"input_tokens"`
+        OutputTokens int `json:"output_tokens"`
+    } `json:"usage"`
+    Error *struct {
+        Message string `json:"message"`
+    } `json:"error"`
+}
+
+// historyMessages rebuilds the prior turns of sessionID from SessionStore as
+// Anthropic Messages API turns. The Messages API is stateless, unlike the
+// claude CLI's --resume that Pool relies on, so this is what makes
+// httpBackend's SessionID behave like an actually resumed conversation
+// instead of just a label. An unknown or empty sessionID (a new
+// conversation) just yields no prior history.
+func historyMessages(sessionID string) []anthropicMessage {
+    if sessionID == "" {
+        return nil
+    }
+    sess, err := sessionStore.Get(sessionID)
+    if err != nil {
+        return nil
+    }
+
+    messages := make([]anthropicMessage, 0, len(sess.Records)*2)
+    for _, rec := range sess.Records {
+        messages = append(messages,
+            anthropicMessage{Role: "user", Content: rec.UserMessage},
+            anthropicMessage{Role: "assistant", Content: rec.ClaudeResult},
+        )
+    }
+    return messages
+}
+
+func (b *httpBackend) Send(ctx context.Context, req ChatRequest) (*ClaudeResponse, error) {
+    messages := append(historyMessages(req.SessionID), anthropicMessage{Role: "user", Content: req.Message})
+
+    body, err := json.Marshal(anthropicRequest{
+        Model:     b.model,
+        MaxTokens: 4096,
+        Messages:  messages,
+    })
+    if err != nil {
+        return nil, err
+    }
+
+    httpReq, err := http.NewRequestWithContext(ctx, http.MethodPost, anthropicMessagesURL, bytes.NewReader(body))
+    if err != nil {
+        return nil, err
+    }
+    httpReq.Header.Set("Content-Type", "application/json")
+    httpReq.Header.Set("x-api-key", b.apiKey)
+    httpReq.Header.Set("anthropic-version", "2023-06-01")
+
+    resp, err := b.client.Do(httpReq)
+    if err != nil {
+        return nil, fmt.Errorf("anthropic request failed: %w", err)
+    }
+    defer resp.Body.Close()
+
+    var aresp anthropicResponse
+    if err := json.NewDecoder(resp.Body).Decode(&aresp); err != nil {
+        return nil, fmt.Errorf("decode anthropic response: %w", err)
+    }
+    if aresp.Error != nil {
+        return &ClaudeResponse{IsError: true, Result: aresp.Error.Message}, nil
+    }
+
+    var text string
+    if len(aresp.Content) > 0 {
+        text = aresp.Content[0].Text
+    }
+
+    sessionID := req.SessionID
+    if sessionID == "" {
+        sessionID = newSessionID()
+    }
+
+    return &ClaudeResponse{
+        Type:      "result",
+        Subtype:   "success",
+        Result:    text,
+        SessionID: sessionID,
+        CostUSD:   estimateCostUSD(b.model, aresp.Usage.InputTokens, aresp.Usage.OutputTokens),
+        NumTurns:  1,
+    }, nil
+}
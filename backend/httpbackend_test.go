@@ -0,0 +1,33 @@
+package main
+
+import "testing"
+
+func TestHistoryMessages(t *testing.T) {
+    sessionStore = newMemorySessionStore()
+
+    if got := historyMessages(""); got != nil {
+        t.Errorf("historyMessages(\"\") = %v, want nil", got)
+    }
+    if got := historyMessages("unknown"); got != nil {
+        t.Errorf("historyMessages(unknown) = %v, want nil", got)
+    }
+
+    sessionStore.Append("sess-1", SessionRecord{UserMessage: "hi", ClaudeResult: "hello"})
+    sessionStore.Append("sess-1", SessionRecord{UserMessage: "how are you", ClaudeResult: "great"})
+
+    got := historyMessages("sess-1")
+    want := []anthropicMessage{
+        {Role: "user", Content: "hi"},
+        {Role: "assistant", Content: "hello"},
+        {Role: "user", Content: "how are you"},
+        {Role: "assistant", Content: "great"},
+    }
+    if len(got) != len(want) {
+        t.Fatalf("historyMessages(sess-1) = %v, want %v", got, want)
+    }
+    for i := range want {
+        if got[i] != want[i] {
+            t.Errorf("message %d = %+v, want %+v", i, got[i], want[i])
+        }
+    }
+}
@@ -0,0 +1,31 @@
+package main
+
+import (
+    "encoding/json"
+    "net/http"
+    "strings"
+)
+
+// sendJSON writes v as a JSON response body with a 200 status. It centralizes
+// the Content-Type header and encoding that used to be duplicated in every
+// handler.
+func sendJSON(w http.ResponseWriter, r *http.Request, v any) {
+    w.Header().Set("Content-Type", "application/json")
+    json.NewEncoder(w).Encode(v)
+}
+
+// readJSON decodes r's body into v, rejecting non-JSON request bodies with
+// 415 and malformed ones with 400. It returns false (and has already written
+// the error response) if decoding failed.
+func readJSON(w http.ResponseWriter, r *http.Request, v any) bool {
+    if ct := r.Header.Get("Content-Type"); ct != "" && !strings.HasPrefix(ct, "application/json") {
+        http.Error(w, "Content-Type must be application/json", http.StatusUnsupportedMediaType)
+        return false
+    }
+
+    if err := json.NewDecoder(r.Body).Decode(v); err != nil {
+        http.Error(w, "Invalid JSON", http.StatusBadRequest)
+        return false
+    }
+    return true
+}
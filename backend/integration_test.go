@@ -0,0 +1,65 @@
+package main
+
+import (
+    "bufio"
+    "net/http"
+    "net/http/httptest"
+    "strings"
+    "testing"
+
+    "github.com/gorilla/websocket"
+)
+
+// newTestHandler builds the real middleware chain (withAuth/withRecover/
+// withLogging/withRequestID/withCORS over the router) the way main() does,
+// so these tests exercise the same wrapping that broke statusRecorder's
+// Flush/Hijack forwarding.
+func newTestHandler(t *testing.T) http.Handler {
+    t.Helper()
+    backend = &Pool{} // chatHandler is unused by these tests; only routing/middleware matters
+    sessionStore = newMemorySessionStore()
+    return buildHandler(NewRateLimiter(1000, 1000), 0, newPowStore(), false, "", corsConfigFromEnv())
+}
+
+// TestChatStreamThroughMiddleware exercises /api/chat/stream through the
+// full middleware chain: withLogging's statusRecorder must still forward
+// Flush, or chatStreamHandler's `w.(http.Flusher)` assertion fails and every
+// call 500s with "Streaming unsupported" before a single byte is streamed.
+func TestChatStreamThroughMiddleware(t *testing.T) {
+    srv := httptest.NewServer(newTestHandler(t))
+    defer srv.Close()
+
+    resp, err := http.Post(srv.URL+"/api/chat/stream", "application/json", strings.NewReader(`{"message":"hi"}`))
+    if err != nil {
+        t.Fatalf("POST /api/chat/stream: %v", err)
+    }
+    defer resp.Body.Close()
+
+    if resp.StatusCode != http.StatusOK {
+        t.Fatalf("status = %d, want 200 (statusRecorder must forward Flush for SSE to work)", resp.StatusCode)
+    }
+    if ct := resp.Header.Get("Content-Type"); ct != "text/event-stream" {
+        t.Errorf("Content-Type = %q, want text/event-stream", ct)
+    }
+}
+
+// TestWsUpgradeThroughMiddleware exercises /api/ws through the full
+// middleware chain: withLogging's statusRecorder must still forward
+// Hijack, or the websocket upgrade fails with "response does not
+// implement http.Hijacker".
+func TestWsUpgradeThroughMiddleware(t *testing.T) {
+    srv := httptest.NewServer(newTestHandler(t))
+    defer srv.Close()
+
+    wsURL := "ws" + strings.TrimPrefix(srv.URL, "http") + "/api/ws"
+    conn, resp, err := websocket.DefaultDialer.Dial(wsURL, nil)
+    if err != nil {
+        body := ""
+        if resp != nil {
+            b, _ := bufio.NewReader(resp.Body).ReadString(0)
+            body = b
+        }
+        t.Fatalf("dial /api/ws: %v (body: %s)", err, body)
+    }
+    defer conn.Close()
+}
@@ -1,14 +1,62 @@
 package main
 
 import (
-    "encoding/json"
+    "context"
     "fmt"
     "log"
     "net/http"
+    "os"
     "os/exec"
+    "strconv"
     "time"
 )
 
+// backend is the ClaudeBackend chatHandler dispatches to. It's chosen once
+// in main() based on config, so handlers never shell out directly.
+var backend ClaudeBackend
+
+const chatRequestTimeout = 60 * time.Second
+
+// newBackendFromEnv picks a ClaudeBackend based on environment config:
+// ANTHROPIC_API_KEY selects the direct HTTP backend, otherwise a pool of
+// CLAUDE_POOL_SIZE (default 4) warm `claude` processes is used.
+func newBackendFromEnv() (ClaudeBackend, error) {
+    if apiKey := os.Getenv("ANTHROPIC_API_KEY"); apiKey != "" {
+        model := os.Getenv("ANTHROPIC_MODEL")
+        if model == "" {
+            model = "claude-sonnet-4-5"
+        }
+        return NewHTTPBackend(apiKey, model), nil
+    }
+
+    size := 4
+    if v := os.Getenv("CLAUDE_POOL_SIZE"); v != "" {
+        if n, err := strconv.Atoi(v); err == nil && n > 0 {
+            size = n
+        }
+    }
+    return NewPool(size)
+}
+
+func envFloat(name string, def float64) float64 {
+    if v := os.Getenv(name); v != "" {
+        if f, err := strconv.ParseFloat(v, 64); err == nil {
+            return f
+        }
+    }
+    return def
+}
+
+// newSessionStoreFromEnv returns a bbolt-backed SessionStore when
+// SESSION_DB_PATH is set, otherwise an in-memory one that won't survive a
+// restart.
+func newSessionStoreFromEnv() (SessionStore, error) {
+    if path := os.Getenv("SESSION_DB_PATH"); path != "" {
+        return newBoltSessionStore(path)
+    }
+    return newMemorySessionStore(), nil
+}
+
 type ChatRequest struct {
     Message   string `json:"message"`
     SessionID string `json:"sessionId,omitempty"`
@@ -35,62 +83,21 @@ type ClaudeResponse struct {
     IsError     bool    `json:"is_error"`
 }
 
-func enableCORS(w http.ResponseWriter) {
-    w.Header().Set("Access-Control-Allow-Origin", "*")
-    w.Header().Set("Access-Control-Allow-Methods", "POST, OPTIONS")
-    w.Header().Set("Access-Control-Allow-Headers", "Content-Type")
-}
-
 func chatHandler(w http.ResponseWriter, r *http.Request) {
-    enableCORS(w)
-    
-    if r.Method == "OPTIONS" {
-        w.WriteHeader(http.StatusOK)
-        return
-    }
-
-    if r.Method != "POST" {
-        http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
-        return
-    }
-
     var req ChatRequest
-    if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
-        http.Error(w, "Invalid JSON", http.StatusBadRequest)
+    if !readJSON(w, r, &req) {
         return
     }
 
-    // Build claude command
-    args := []string{"-p", req.Message, "--output-format", "json"}
-    
-    // Add session continuation if sessionID provided
-    if req.SessionID != "" {
-        args = append(args, "--resume", req.SessionID)
-    }
+    ctx, cancel := context.WithTimeout(r.Context(), chatRequestTimeout)
+    defer cancel()
 
-    // Execute claude command
-    cmd := exec.Command("claude", args...)
-    output, err := cmd.Output()
-    
+    claudeResp, err := backend.Send(ctx, req)
     if err != nil {
-        response := ChatResponse{
+        sendJSON(w, r, ChatResponse{
             Success: false,
             Error:   fmt.Sprintf("Claude command failed: %v", err),
-        }
-        w.Header().Set("Content-Type", "application/json")
-        json.NewEncoder(w).Encode(response)
-        return
-    }
-
-    // Parse claude response
-    var claudeResp ClaudeResponse
-    if err := json.Unmarshal(output, &claudeResp); err != nil {
-        response := ChatResponse{
-            Success: false,
-            Error:   fmt.Sprintf("Failed to parse Claude response: %v", err),
-        }
-        w.Header().Set("Content-Type", "application/json")
-        json.NewEncoder(w).Encode(response)
+        })
         return
     }
 
@@ -108,35 +115,91 @@ func chatHandler(w http.ResponseWriter, r *http.Request) {
         response.Error = claudeResp.Result
     }
 
-    w.Header().Set("Content-Type", "application/json")
-    json.NewEncoder(w).Encode(response)
+    recordTurn(req, claudeResp)
+
+    sendJSON(w, r, response)
 }
 
 func healthHandler(w http.ResponseWriter, r *http.Request) {
-    enableCORS(w)
-    
     // Check if claude is available
     cmd := exec.Command("claude", "--version")
     err := cmd.Run()
-    
-    status := map[string]interface{}{
-        "status": "ok",
+
+    sendJSON(w, r, map[string]interface{}{
+        "status":           "ok",
         "claude_available": err == nil,
-        "timestamp": time.Now().Unix(),
+        "timestamp":        time.Now().Unix(),
+    })
+}
+
+// buildHandler wires up the router, abuse-mitigation middleware and the
+// outer middleware chain. It's split out of main() so tests can exercise
+// the real chain (e.g. that withLogging's statusRecorder still forwards
+// Flush/Hijack to /api/chat/stream and /api/ws) without starting a process.
+func buildHandler(rateLimiter *RateLimiter, maxDailyCostUSD float64, powChallenges *powStore, powEnabled bool, authToken string, cors CORSConfig) http.Handler {
+    // protect applies the same abuse mitigation (rate limit, optional PoW,
+    // daily cost budget) to every endpoint that spends real Claude/Anthropic
+    // API cost, not just /api/chat.
+    protect := func(next http.HandlerFunc) http.HandlerFunc {
+        protected := withRateLimit(rateLimiter, withCostBudget(maxDailyCostUSD, next))
+        if powEnabled {
+            protected = withRateLimit(rateLimiter, withPoW(powChallenges, withCostBudget(maxDailyCostUSD, next)))
+        }
+        return protected
     }
-    
-    w.Header().Set("Content-Type", "application/json")
-    json.NewEncoder(w).Encode(status)
+
+    router := NewRouter()
+    router.Handle(http.MethodPost, "/api/chat", protect(chatHandler))
+    router.Handle(http.MethodPost, "/api/chat/stream", protect(chatStreamHandler))
+    router.Handle(http.MethodGet, "/api/health", healthHandler)
+    router.Handle(http.MethodGet, "/api/sessions", sessionsListHandler)
+    router.Handle(http.MethodGet, "/api/sessions/", sessionGetHandler)
+    router.Handle(http.MethodDelete, "/api/sessions/", sessionDeleteHandler)
+    router.Handle(http.MethodGet, "/api/pow/challenge", powChallengeHandler(powChallenges))
+    router.Handle(http.MethodGet, "/api/ws", protect(newWsHandler(rateLimiter, maxDailyCostUSD)))
+
+    var handler http.Handler = router
+    handler = withAuth(authToken, handler)
+    handler = withRecover(handler)
+    handler = withLogging(handler)
+    handler = withRequestID(handler)
+    handler = withCORS(cors, handler)
+    return handler
 }
 
 func main() {
-    http.HandleFunc("/api/chat", chatHandler)
-    http.HandleFunc("/api/health", healthHandler)
-    
+    b, err := newBackendFromEnv()
+    if err != nil {
+        log.Fatalf("failed to start claude backend: %v", err)
+    }
+    backend = b
+    if pool, ok := backend.(*Pool); ok {
+        defer pool.Close()
+    }
+
+    store, err := newSessionStoreFromEnv()
+    if err != nil {
+        log.Fatalf("failed to start session store: %v", err)
+    }
+    sessionStore = store
+
+    rateLimiter := NewRateLimiter(envFloat("RATE_LIMIT_RPS", 1), envFloat("RATE_LIMIT_BURST", 5))
+    maxDailyCostUSD := envFloat("MAX_DAILY_COST_USD", 0)
+    powChallenges := newPowStore()
+    powEnabled := os.Getenv("POW_ENABLED") == "true"
+
+    handler := buildHandler(rateLimiter, maxDailyCostUSD, powChallenges, powEnabled, os.Getenv("API_AUTH_TOKEN"), corsConfigFromEnv())
+
     fmt.Println("🚀 Server starting on :8080")
     fmt.Println("📋 Endpoints:")
     fmt.Println("  POST /api/chat - Send chat message")
+    fmt.Println("  POST /api/chat/stream - Stream chat response via SSE")
     fmt.Println("  GET  /api/health - Health check")
-    
-    log.Fatal(http.ListenAndServe(":8080", nil))
+    fmt.Println("  GET  /api/sessions - List sessions")
+    fmt.Println("  GET  /api/sessions/{id} - Replay a session")
+    fmt.Println("  DELETE /api/sessions/{id} - Delete a session")
+    fmt.Println("  GET  /api/pow/challenge - Get a proof-of-work challenge")
+    fmt.Println("  GET  /api/ws - Bidirectional chat over WebSocket")
+
+    log.Fatal(http.ListenAndServe(":8080", handler))
 }
\ No newline at end of file
@@ -0,0 +1,114 @@
+package main
+
+import (
+    "bufio"
+    "context"
+    "crypto/rand"
+    "crypto/subtle"
+    "encoding/hex"
+    "fmt"
+    "log"
+    "net"
+    "net/http"
+    "time"
+)
+
+type contextKey string
+
+const requestIDKey contextKey = "requestID"
+
+// withRequestID generates a short request ID, stashes it on the request
+// context for downstream handlers/logging, and echoes it back as a header.
+func withRequestID(next http.Handler) http.Handler {
+    return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+        id := newRequestID()
+        w.Header().Set("X-Request-Id", id)
+        ctx := context.WithValue(r.Context(), requestIDKey, id)
+        next.ServeHTTP(w, r.WithContext(ctx))
+    })
+}
+
+func newRequestID() string {
+    b := make([]byte, 8)
+    if _, err := rand.Read(b); err != nil {
+        return "unknown"
+    }
+    return hex.EncodeToString(b)
+}
+
+func requestIDFromContext(ctx context.Context) string {
+    id, _ := ctx.Value(requestIDKey).(string)
+    return id
+}
+
+// statusRecorder wraps a ResponseWriter so withLogging can see the status
+// code a handler actually wrote. It forwards Flush and Hijack so wrapping it
+// doesn't strip the optional interfaces chatStreamHandler (SSE) and
+// wsHandler (websocket upgrade) depend on.
+type statusRecorder struct {
+    http.ResponseWriter
+    status int
+}
+
+func (s *statusRecorder) WriteHeader(status int) {
+    s.status = status
+    s.ResponseWriter.WriteHeader(status)
+}
+
+func (s *statusRecorder) Flush() {
+    if f, ok := s.ResponseWriter.(http.Flusher); ok {
+        f.Flush()
+    }
+}
+
+func (s *statusRecorder) Hijack() (net.Conn, *bufio.ReadWriter, error) {
+    h, ok := s.ResponseWriter.(http.Hijacker)
+    if !ok {
+        return nil, nil, fmt.Errorf("underlying ResponseWriter does not support http.Hijacker")
+    }
+    return h.Hijack()
+}
+
+// withLogging logs method, path, status and latency for every request.
+func withLogging(next http.Handler) http.Handler {
+    return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+        start := time.Now()
+        rec := &statusRecorder{ResponseWriter: w, status: http.StatusOK}
+
+        next.ServeHTTP(rec, r)
+
+        log.Printf("[%s] %s %s -> %d (%s)", requestIDFromContext(r.Context()), r.Method, r.URL.Path, rec.status, time.Since(start))
+    })
+}
+
+// withRecover turns a panicking handler into a 500 instead of killing the
+// server.
+func withRecover(next http.Handler) http.Handler {
+    return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+        defer func() {
+            if rec := recover(); rec != nil {
+                log.Printf("[%s] panic: %v", requestIDFromContext(r.Context()), rec)
+                http.Error(w, "Internal server error", http.StatusInternalServerError)
+            }
+        }()
+        next.ServeHTTP(w, r)
+    })
+}
+
+// withAuth requires a "Bearer <API_AUTH_TOKEN>" Authorization header on
+// every request when API_AUTH_TOKEN is set; it's a no-op otherwise so local
+// development doesn't need a token.
+func withAuth(token string, next http.Handler) http.Handler {
+    if token == "" {
+        return next
+    }
+    want := "Bearer " + token
+    return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+        got := r.Header.Get("Authorization")
+        if subtle.ConstantTimeCompare([]byte(got), []byte(want)) != 1 {
+            http.Error(w, "Unauthorized", http.StatusUnauthorized)
+            return
+        }
+        next.ServeHTTP(w, r)
+    })
+}
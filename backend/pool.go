@@ -0,0 +1,195 @@
+package main
+
+import (
+    "bufio"
+    "context"
+    "encoding/json"
+    "fmt"
+    "io"
+    "log"
+    "os/exec"
+    "sync"
+    "sync/atomic"
+)
+
+// workerRequest is the JSON line written to a pool worker's stdin; the reply
+// is read back off stdout as stream-json lines ending in a ClaudeResponse.
+type workerRequest struct {
+    Message   string `json:"message"`
+    SessionID string `json:"session_id,omitempty"`
+}
+
+// poolWorker is one long-lived `claude` process. Requests are sent one at a
+// time on stdin and the matching response is read back off stdout, so
+// callers must hold the worker exclusively for the duration of a Send.
+type poolWorker struct {
+    mu     sync.Mutex
+    cmd    *exec.Cmd
+    stdin  io.WriteCloser
+    stdout *bufio.Scanner
+    killed atomic.Bool
+}
+
+// claudeBinary is the executable startWorker invokes. It's a var so tests
+// can point it at a fixture instead of the real claude CLI.
+var claudeBinary = "claude"
+
+func startWorker() (*poolWorker, error) {
+    cmd := exec.Command(claudeBinary, "--input-format", "stream-json", "--output-format", "stream-json")
+
+    stdin, err := cmd.StdinPipe()
+    if err != nil {
+        return nil, fmt.Errorf("open stdin pipe: %w", err)
+    }
+    stdout, err := cmd.StdoutPipe()
+    if err != nil {
+        return nil, fmt.Errorf("open stdout pipe: %w", err)
+    }
+    if err := cmd.Start(); err != nil {
+        return nil, fmt.Errorf("start claude worker: %w", err)
+    }
+
+    scanner := bufio.NewScanner(stdout)
+    scanner.Buffer(make([]byte, 0, 64*1024), 1024*1024)
+
+    return &poolWorker{cmd: cmd, stdin: stdin, stdout: scanner}, nil
+}
+
+func (w *poolWorker) send(req ChatRequest) (*ClaudeResponse, error) {
+    w.mu.Lock()
+    defer w.mu.Unlock()
+
+    line, err := json.Marshal(workerRequest{Message: req.Message, SessionID: req.SessionID})
+    if err != nil {
+        return nil, err
+    }
+    if _, err := w.stdin.Write(append(line, '\n')); err != nil {
+        return nil, fmt.Errorf("write to worker: %w", err)
+    }
+
+    // stream-json emits one line per turn event (init/system, assistant
+    // deltas, tool_use/tool_result, ...) before the final line carrying
+    // type:"result". Scan past the intermediate lines so they aren't left
+    // in the pipe to be misread as the response to the worker's next
+    // request.
+    for {
+        if !w.stdout.Scan() {
+            if err := w.stdout.Err(); err != nil {
+                return nil, fmt.Errorf("read from worker: %w", err)
+            }
+            return nil, fmt.Errorf("worker closed stdout")
+        }
+
+        var env streamEnvelope
+        if err := json.Unmarshal(w.stdout.Bytes(), &env); err != nil {
+            return nil, fmt.Errorf("parse worker response: %w", err)
+        }
+        if env.Type != "result" {
+            continue
+        }
+
+        var resp ClaudeResponse
+        if err := json.Unmarshal(w.stdout.Bytes(), &resp); err != nil {
+            return nil, fmt.Errorf("parse worker response: %w", err)
+        }
+        return &resp, nil
+    }
+}
+
+func (w *poolWorker) close() {
+    w.stdin.Close()
+    w.cmd.Process.Kill()
+    w.cmd.Wait()
+}
+
+// kill forces the worker's process to exit, unblocking a send() stuck in
+// w.stdout.Scan() on a hung claude process. The worker is marked killed so
+// Pool.Send's background goroutine knows to respawn rather than recycle it.
+func (w *poolWorker) kill() {
+    w.killed.Store(true)
+    w.cmd.Process.Kill()
+}
+
+// Pool is a fixed-size set of warm `claude` processes, dispatched via a
+// buffered channel. It eliminates the ~500ms cold start `exec.Command`
+// pays on every message.
+type Pool struct {
+    workers chan *poolWorker
+}
+
+// NewPool starts size long-lived claude workers and returns a Pool ready to
+// dispatch requests to them.
+func NewPool(size int) (*Pool, error) {
+    p := &Pool{workers: make(chan *poolWorker, size)}
+    for i := 0; i < size; i++ {
+        w, err := startWorker()
+        if err != nil {
+            p.Close()
+            return nil, fmt.Errorf("starting worker %d/%d: %w", i+1, size, err)
+        }
+        p.workers <- w
+    }
+    return p, nil
+}
+
+// Send borrows a worker, dispatches req, and returns it to the pool. It
+// respects ctx so a slow worker can't block a caller past its deadline;
+// if ctx is done first, the worker is only handed back once its in-flight
+// send() actually returns, never while w.mu is still held, so a stuck
+// claude process can't leak a "busy" worker back into circulation. A
+// timed-out worker is killed rather than left running unbounded, and
+// recycle respawns a fresh one in its place so a hung claude process can't
+// permanently shrink the pool.
+func (p *Pool) Send(ctx context.Context, req ChatRequest) (*ClaudeResponse, error) {
+    select {
+    case w := <-p.workers:
+        type result struct {
+            resp *ClaudeResponse
+            err  error
+        }
+        done := make(chan result, 1)
+        go func() {
+            resp, err := w.send(req)
+            done <- result{resp, err}
+            p.recycle(w)
+        }()
+
+        select {
+        case <-ctx.Done():
+            w.kill()
+            return nil, ctx.Err()
+        case r := <-done:
+            return r.resp, r.err
+        }
+    case <-ctx.Done():
+        return nil, ctx.Err()
+    }
+}
+
+// recycle returns w to the pool, unless it was killed after losing a race
+// with a caller's ctx, in which case it respawns a fresh worker to take its
+// place. If the respawn itself fails, the pool is left one worker smaller
+// rather than blocking forever trying to replace it.
+func (p *Pool) recycle(w *poolWorker) {
+    if !w.killed.Load() {
+        p.workers <- w
+        return
+    }
+
+    w.stdin.Close()
+    w.cmd.Wait()
+    fresh, err := startWorker()
+    if err != nil {
+        log.Printf("pool: failed to respawn worker after timeout: %v", err)
+        return
+    }
+    p.workers <- fresh
+}
+
+// Close shuts down every worker in the pool.
+func (p *Pool) Close() {
+    close(p.workers)
+    for w := range p.workers {
+        w.close()
+    }
+}
@@ -0,0 +1,107 @@
+package main
+
+import (
+    "context"
+    "os"
+    "os/exec"
+    "path/filepath"
+    "testing"
+    "time"
+)
+
+// TestMain builds testdata/fakeclaude into a temp binary and points
+// claudeBinary at it, so Pool tests never shell out to the real claude CLI.
+func TestMain(m *testing.M) {
+    dir, err := os.MkdirTemp("", "fakeclaude")
+    if err != nil {
+        panic(err)
+    }
+    defer os.RemoveAll(dir)
+
+    bin := filepath.Join(dir, "fakeclaude")
+    build := exec.Command("go", "build", "-o", bin, "./testdata/fakeclaude")
+    if out, err := build.CombinedOutput(); err != nil {
+        panic("building fakeclaude fixture: " + err.Error() + "\n" + string(out))
+    }
+    claudeBinary = bin
+
+    os.Exit(m.Run())
+}
+
+func TestPoolSend_SkipsIntermediateLines(t *testing.T) {
+    p, err := NewPool(1)
+    if err != nil {
+        t.Fatalf("NewPool: %v", err)
+    }
+    defer p.Close()
+
+    resp, err := p.Send(context.Background(), ChatRequest{Message: "hello"})
+    if err != nil {
+        t.Fatalf("Send: %v", err)
+    }
+    if resp.Result != "echo: hello" {
+        t.Errorf("Result = %q, want %q", resp.Result, "echo: hello")
+    }
+    if resp.CostUSD != 0.01 {
+        t.Errorf("CostUSD = %v, want 0.01", resp.CostUSD)
+    }
+
+    // A second request on the same worker must get its own result, not a
+    // line left over from the first turn's intermediate events.
+    resp2, err := p.Send(context.Background(), ChatRequest{Message: "again"})
+    if err != nil {
+        t.Fatalf("Send (2nd): %v", err)
+    }
+    if resp2.Result != "echo: again" {
+        t.Errorf("Result = %q, want %q", resp2.Result, "echo: again")
+    }
+}
+
+// TestPoolSend_TimeoutRespawnsHungWorker ensures a worker stuck on a hung
+// claude process is killed (not left running forever) and replaced, so
+// repeated timeouts don't permanently shrink the pool to zero.
+func TestPoolSend_TimeoutRespawnsHungWorker(t *testing.T) {
+    p, err := NewPool(1)
+    if err != nil {
+        t.Fatalf("NewPool: %v", err)
+    }
+    defer p.Close()
+
+    ctx, cancel := context.WithTimeout(context.Background(), 50*time.Millisecond)
+    defer cancel()
+    if _, err := p.Send(ctx, ChatRequest{Message: "__hang__"}); err == nil {
+        t.Fatal("Send: want timeout error, got nil")
+    }
+
+    // The only worker just got killed; recycle() respawning it happens in
+    // a background goroutine, so give it a moment to land back in the pool.
+    select {
+    case w := <-p.workers:
+        p.workers <- w
+    case <-time.After(2 * time.Second):
+        t.Fatal("pool never got a replacement worker back after the timeout")
+    }
+
+    resp, err := p.Send(context.Background(), ChatRequest{Message: "hello"})
+    if err != nil {
+        t.Fatalf("Send after respawn: %v", err)
+    }
+    if resp.Result != "echo: hello" {
+        t.Errorf("Result = %q, want %q", resp.Result, "echo: hello")
+    }
+}
+
+func BenchmarkPool_Send(b *testing.B) {
+    p, err := NewPool(4)
+    if err != nil {
+        b.Fatalf("NewPool: %v", err)
+    }
+    defer p.Close()
+
+    b.ResetTimer()
+    for i := 0; i < b.N; i++ {
+        if _, err := p.Send(context.Background(), ChatRequest{Message: "hello"}); err != nil {
+            b.Fatalf("Send: %v", err)
+        }
+    }
+}
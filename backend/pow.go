@@ -0,0 +1,110 @@
+package main
+
+import (
+    "crypto/rand"
+    "crypto/sha256"
+    "encoding/hex"
+    "fmt"
+    "net/http"
+    "strings"
+    "sync"
+    "time"
+)
+
+const (
+    powDifficulty = 16 // leading zero bits required of sha256(seed||nonce)
+    powChallengeTTL = 2 * time.Minute
+)
+
+// powStore tracks challenges that have been issued but not yet redeemed, so
+// each one can only be solved once and expires if never used.
+type powStore struct {
+    mu         sync.Mutex
+    challenges map[string]time.Time // seed -> expiry
+}
+
+func newPowStore() *powStore {
+    return &powStore{challenges: make(map[string]time.Time)}
+}
+
+func (s *powStore) issue() (string, error) {
+    raw := make([]byte, 16)
+    if _, err := rand.Read(raw); err != nil {
+        return "", err
+    }
+    seed := hex.EncodeToString(raw)
+
+    s.mu.Lock()
+    s.challenges[seed] = time.Now().Add(powChallengeTTL)
+    s.mu.Unlock()
+
+    return seed, nil
+}
+
+// redeem checks that seed is an outstanding, unexpired challenge and nonce
+// solves it, consuming the challenge either way.
+func (s *powStore) redeem(seed, nonce string) bool {
+    s.mu.Lock()
+    expiry, ok := s.challenges[seed]
+    delete(s.challenges, seed)
+    s.mu.Unlock()
+
+    if !ok || time.Now().After(expiry) {
+        return false
+    }
+    return hashMeetsDifficulty(seed, nonce, powDifficulty)
+}
+
+func hashMeetsDifficulty(seed, nonce string, difficulty int) bool {
+    sum := sha256.Sum256([]byte(seed + nonce))
+    return leadingZeroBits(sum[:]) >= difficulty
+}
+
+func leadingZeroBits(b []byte) int {
+    bits := 0
+    for _, byt := range b {
+        if byt == 0 {
+            bits += 8
+            continue
+        }
+        for mask := byte(0x80); mask > 0; mask >>= 1 {
+            if byt&mask != 0 {
+                return bits
+            }
+            bits++
+        }
+    }
+    return bits
+}
+
+type powChallengeResponse struct {
+    Seed       string `json:"seed"`
+    Difficulty int    `json:"difficulty"`
+}
+
+func powChallengeHandler(store *powStore) http.HandlerFunc {
+    return func(w http.ResponseWriter, r *http.Request) {
+        seed, err := store.issue()
+        if err != nil {
+            http.Error(w, fmt.Sprintf("Failed to issue challenge: %v", err), http.StatusInternalServerError)
+            return
+        }
+
+        sendJSON(w, r, powChallengeResponse{Seed: seed, Difficulty: powDifficulty})
+    }
+}
+
+// withPoW rejects requests that don't carry a valid X-PoW-Solution header
+// of the form "seed:nonce" redeeming a challenge issued by
+// GET /api/pow/challenge.
+func withPoW(store *powStore, next http.HandlerFunc) http.HandlerFunc {
+    return func(w http.ResponseWriter, r *http.Request) {
+        solution := r.Header.Get("X-PoW-Solution")
+        seed, nonce, ok := strings.Cut(solution, ":")
+        if !ok || !store.redeem(seed, nonce) {
+            http.Error(w, "Missing or invalid proof of work", http.StatusForbidden)
+            return
+        }
+        next(w, r)
+    }
+}
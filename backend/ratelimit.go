@@ -0,0 +1,88 @@
+package main
+
+import (
+    "net"
+    "net/http"
+    "sync"
+    "time"
+)
+
+// tokenBucket is a classic token-bucket limiter: tokens refill at rate per
+// second up to capacity, and each request consumes one.
+type tokenBucket struct {
+    mu       sync.Mutex
+    tokens   float64
+    capacity float64
+    rate     float64
+    last     time.Time
+}
+
+func newTokenBucket(rate, capacity float64) *tokenBucket {
+    return &tokenBucket{tokens: capacity, capacity: capacity, rate: rate, last: time.Now()}
+}
+
+func (b *tokenBucket) allow() bool {
+    b.mu.Lock()
+    defer b.mu.Unlock()
+
+    now := time.Now()
+    elapsed := now.Sub(b.last).Seconds()
+    b.last = now
+
+    b.tokens += elapsed * b.rate
+    if b.tokens > b.capacity {
+        b.tokens = b.capacity
+    }
+    if b.tokens < 1 {
+        return false
+    }
+    b.tokens--
+    return true
+}
+
+// RateLimiter hands out a token bucket per client IP so one caller can't
+// burn through the shared Claude budget on its own.
+type RateLimiter struct {
+    mu      sync.Mutex
+    buckets map[string]*tokenBucket
+    rps     float64
+    burst   float64
+}
+
+// NewRateLimiter returns a RateLimiter allowing rps requests/sec per IP,
+// with bursts up to burst requests.
+func NewRateLimiter(rps, burst float64) *RateLimiter {
+    return &RateLimiter{buckets: make(map[string]*tokenBucket), rps: rps, burst: burst}
+}
+
+func (rl *RateLimiter) allow(ip string) bool {
+    rl.mu.Lock()
+    b, ok := rl.buckets[ip]
+    if !ok {
+        b = newTokenBucket(rl.rps, rl.burst)
+        rl.buckets[ip] = b
+    }
+    rl.mu.Unlock()
+
+    return b.allow()
+}
+
+func clientIP(r *http.Request) string {
+    host, _, err := net.SplitHostPort(r.RemoteAddr)
+    if err != nil {
+        return r.RemoteAddr
+    }
+    return host
+}
+
+// withRateLimit rejects requests from an IP once it has exhausted its token
+// bucket, with a 429 Too Many Requests.
+func withRateLimit(rl *RateLimiter, next http.HandlerFunc) http.HandlerFunc {
+    return func(w http.ResponseWriter, r *http.Request) {
+        if !rl.allow(clientIP(r)) {
+            http.Error(w, "Rate limit exceeded", http.StatusTooManyRequests)
+            return
+        }
+        next(w, r)
+    }
+}
@@ -0,0 +1,42 @@
+package main
+
+import "net/http"
+
+// Router dispatches by method within a single path pattern, so e.g.
+// GET and DELETE on /api/sessions/ can be registered separately instead of
+// a handler switching on r.Method itself.
+type Router struct {
+    mux    *http.ServeMux
+    routes map[string]map[string]http.HandlerFunc
+}
+
+// NewRouter returns an empty Router ready for Handle calls.
+func NewRouter() *Router {
+    return &Router{mux: http.NewServeMux(), routes: make(map[string]map[string]http.HandlerFunc)}
+}
+
+// Handle registers h to serve method requests on pattern.
+func (rt *Router) Handle(method, pattern string, h http.HandlerFunc) {
+    methods, ok := rt.routes[pattern]
+    if !ok {
+        methods = make(map[string]http.HandlerFunc)
+        rt.routes[pattern] = methods
+        rt.mux.HandleFunc(pattern, rt.dispatch(pattern))
+    }
+    methods[method] = h
+}
+
+func (rt *Router) dispatch(pattern string) http.HandlerFunc {
+    return func(w http.ResponseWriter, r *http.Request) {
+        h, ok := rt.routes[pattern][r.Method]
+        if !ok {
+            http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+            return
+        }
+        h(w, r)
+    }
+}
+
+func (rt *Router) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+    rt.mux.ServeHTTP(w, r)
+}
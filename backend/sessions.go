@@ -0,0 +1,155 @@
+package main
+
+import (
+    "fmt"
+    "log"
+    "net/http"
+    "strings"
+    "sync"
+    "time"
+)
+
+// SessionRecord is one chat turn: the message the user sent, what claude
+// returned, and what it cost.
+type SessionRecord struct {
+    UserMessage  string  `json:"userMessage"`
+    ClaudeResult string  `json:"claudeResult"`
+    Cost         float64 `json:"cost"`
+    Turns        int     `json:"turns"`
+    Timestamp    int64   `json:"timestamp"`
+}
+
+// Session is the full history of one sessionID, so the frontend can resume
+// or replay a conversation after the server restarts.
+type Session struct {
+    ID        string          `json:"id"`
+    Records   []SessionRecord `json:"records"`
+    TotalCost float64         `json:"totalCost"`
+}
+
+// SessionStore persists chat turns keyed by sessionID. The in-memory
+// implementation is the default; a bbolt-backed one is used when
+// SESSION_DB_PATH is set so sessions survive a restart.
+type SessionStore interface {
+    Append(sessionID string, rec SessionRecord) error
+    List() ([]*Session, error)
+    Get(sessionID string) (*Session, error)
+    Delete(sessionID string) error
+}
+
+// memorySessionStore is a SessionStore that keeps everything in a map; it
+// does not survive a restart.
+type memorySessionStore struct {
+    mu       sync.RWMutex
+    sessions map[string]*Session
+}
+
+func newMemorySessionStore() *memorySessionStore {
+    return &memorySessionStore{sessions: make(map[string]*Session)}
+}
+
+func (s *memorySessionStore) Append(sessionID string, rec SessionRecord) error {
+    s.mu.Lock()
+    defer s.mu.Unlock()
+
+    sess, ok := s.sessions[sessionID]
+    if !ok {
+        sess = &Session{ID: sessionID}
+        s.sessions[sessionID] = sess
+    }
+    sess.Records = append(sess.Records, rec)
+    sess.TotalCost += rec.Cost
+    return nil
+}
+
+func (s *memorySessionStore) List() ([]*Session, error) {
+    s.mu.RLock()
+    defer s.mu.RUnlock()
+
+    out := make([]*Session, 0, len(s.sessions))
+    for _, sess := range s.sessions {
+        out = append(out, sess)
+    }
+    return out, nil
+}
+
+func (s *memorySessionStore) Get(sessionID string) (*Session, error) {
+    s.mu.RLock()
+    defer s.mu.RUnlock()
+
+    sess, ok := s.sessions[sessionID]
+    if !ok {
+        return nil, fmt.Errorf("session %q not found", sessionID)
+    }
+    return sess, nil
+}
+
+func (s *memorySessionStore) Delete(sessionID string) error {
+    s.mu.Lock()
+    defer s.mu.Unlock()
+
+    delete(s.sessions, sessionID)
+    return nil
+}
+
+// sessionStore is the store chatHandler and the /api/sessions endpoints
+// share. It's chosen once in main() based on config.
+var sessionStore SessionStore
+
+func recordTurn(req ChatRequest, resp *ClaudeResponse) {
+    if resp.SessionID == "" {
+        return
+    }
+    err := sessionStore.Append(resp.SessionID, SessionRecord{
+        UserMessage:  req.Message,
+        ClaudeResult: resp.Result,
+        Cost:         resp.CostUSD,
+        Turns:        resp.NumTurns,
+        Timestamp:    time.Now().Unix(),
+    })
+    if err != nil {
+        log.Printf("failed to record session turn: %v", err)
+    }
+}
+
+func sessionsListHandler(w http.ResponseWriter, r *http.Request) {
+    sessions, err := sessionStore.List()
+    if err != nil {
+        http.Error(w, fmt.Sprintf("Failed to list sessions: %v", err), http.StatusInternalServerError)
+        return
+    }
+    sendJSON(w, r, sessions)
+}
+
+func sessionIDFromPath(r *http.Request) string {
+    return strings.TrimPrefix(r.URL.Path, "/api/sessions/")
+}
+
+func sessionGetHandler(w http.ResponseWriter, r *http.Request) {
+    id := sessionIDFromPath(r)
+    if id == "" {
+        http.Error(w, "Missing session id", http.StatusBadRequest)
+        return
+    }
+
+    sess, err := sessionStore.Get(id)
+    if err != nil {
+        http.Error(w, err.Error(), http.StatusNotFound)
+        return
+    }
+    sendJSON(w, r, sess)
+}
+
+func sessionDeleteHandler(w http.ResponseWriter, r *http.Request) {
+    id := sessionIDFromPath(r)
+    if id == "" {
+        http.Error(w, "Missing session id", http.StatusBadRequest)
+        return
+    }
+
+    if err := sessionStore.Delete(id); err != nil {
+        http.Error(w, err.Error(), http.StatusInternalServerError)
+        return
+    }
+    w.WriteHeader(http.StatusNoContent)
+}
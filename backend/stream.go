@@ -0,0 +1,120 @@
+package main
+
+import (
+    "bufio"
+    "context"
+    "encoding/json"
+    "fmt"
+    "net/http"
+    "os/exec"
+    "strings"
+    "time"
+)
+
+// streamEnvelope is just enough of each stream-json line to route it to the
+// right SSE event name; the full line is forwarded as-is in the data field.
+type streamEnvelope struct {
+    Type string `json:"type"`
+}
+
+const heartbeatInterval = 15 * time.Second
+
+func writeSSEEvent(w http.ResponseWriter, flusher http.Flusher, event string, data []byte) {
+    fmt.Fprintf(w, "event: %s\n", event)
+    for _, line := range strings.Split(string(data), "\n") {
+        fmt.Fprintf(w, "data: %s\n", line)
+    }
+    fmt.Fprint(w, "\n")
+    flusher.Flush()
+}
+
+func chatStreamHandler(w http.ResponseWriter, r *http.Request) {
+    var req ChatRequest
+    if !readJSON(w, r, &req) {
+        return
+    }
+
+    flusher, ok := w.(http.Flusher)
+    if !ok {
+        http.Error(w, "Streaming unsupported", http.StatusInternalServerError)
+        return
+    }
+
+    args := []string{"-p", req.Message, "--output-format", "stream-json"}
+    if req.SessionID != "" {
+        args = append(args, "--resume", req.SessionID)
+    }
+
+    // exec.CommandContext kills the child process as soon as the request
+    // context is done, so an aborted browser doesn't leave a claude process
+    // running.
+    cmd := exec.CommandContext(r.Context(), "claude", args...)
+    stdout, err := cmd.StdoutPipe()
+    if err != nil {
+        http.Error(w, fmt.Sprintf("Failed to open stdout pipe: %v", err), http.StatusInternalServerError)
+        return
+    }
+
+    w.Header().Set("Content-Type", "text/event-stream")
+    w.Header().Set("Cache-Control", "no-cache")
+    w.Header().Set("Connection", "keep-alive")
+    w.WriteHeader(http.StatusOK)
+    flusher.Flush()
+
+    if err := cmd.Start(); err != nil {
+        writeSSEEvent(w, flusher, "error", []byte(fmt.Sprintf(`{"error":"failed to start claude: %v"}`, err)))
+        return
+    }
+
+    done := make(chan struct{})
+    defer close(done)
+    go sendHeartbeats(r.Context(), w, flusher, done)
+
+    scanner := bufio.NewScanner(stdout)
+    scanner.Buffer(make([]byte, 0, 64*1024), 1024*1024)
+    for scanner.Scan() {
+        line := scanner.Bytes()
+        if len(line) == 0 {
+            continue
+        }
+
+        var env streamEnvelope
+        if err := json.Unmarshal(line, &env); err != nil {
+            continue
+        }
+
+        event := env.Type
+        if event == "" {
+            event = "message"
+        }
+        writeSSEEvent(w, flusher, event, line)
+
+        if event == "result" {
+            var claudeResp ClaudeResponse
+            if err := json.Unmarshal(line, &claudeResp); err == nil {
+                recordTurn(req, &claudeResp)
+            }
+        }
+    }
+
+    if err := cmd.Wait(); err != nil && r.Context().Err() == nil {
+        writeSSEEvent(w, flusher, "error", []byte(fmt.Sprintf(`{"error":"claude command failed: %v"}`, err)))
+    }
+}
+
+func sendHeartbeats(ctx context.Context, w http.ResponseWriter, flusher http.Flusher, done <-chan struct{}) {
+    ticker := time.NewTicker(heartbeatInterval)
+    defer ticker.Stop()
+
+    for {
+        select {
+        case <-ctx.Done():
+            return
+        case <-done:
+            return
+        case <-ticker.C:
+            fmt.Fprint(w, ": heartbeat\n\n")
+            flusher.Flush()
+        }
+    }
+}
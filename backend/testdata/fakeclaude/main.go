@@ -0,0 +1,51 @@
+// Command fakeclaude stands in for the real `claude` CLI in pool_test.go.
+// For every stream-json request line on stdin it writes a handful of
+// intermediate event lines followed by a final type:"result" line, mirroring
+// the shape pool.go's worker protocol has to cope with.
+package main
+
+import (
+    "bufio"
+    "encoding/json"
+    "fmt"
+    "os"
+    "time"
+)
+
+type request struct {
+    Message   string `json:"message"`
+    SessionID string `json:"session_id,omitempty"`
+}
+
+func main() {
+    scanner := bufio.NewScanner(os.Stdin)
+    scanner.Buffer(make([]byte, 0, 64*1024), 1024*1024)
+    out := bufio.NewWriter(os.Stdout)
+    defer out.Flush()
+
+    for scanner.Scan() {
+        var req request
+        if err := json.Unmarshal(scanner.Bytes(), &req); err != nil {
+            continue
+        }
+
+        // Simulates a hung claude process: never writes a response, so
+        // tests can exercise what happens when a caller's ctx times out
+        // waiting on it.
+        if req.Message == "__hang__" {
+            time.Sleep(time.Hour)
+        }
+
+        fmt.Fprintln(out, `{"type":"system","subtype":"init"}`)
+        fmt.Fprintln(out, `{"type":"assistant","message":{"content":[{"type":"text","text":"thinking..."}]}}`)
+
+        result, _ := json.Marshal(map[string]any{
+            "type":       "result",
+            "result":     "echo: " + req.Message,
+            "session_id": "fake-session",
+            "cost_usd":   0.01,
+        })
+        fmt.Fprintln(out, string(result))
+        out.Flush()
+    }
+}
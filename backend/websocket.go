@@ -0,0 +1,215 @@
+package main
+
+import (
+    "bufio"
+    "context"
+    "encoding/json"
+    "log"
+    "net/http"
+    "os/exec"
+    "sync"
+
+    "github.com/gorilla/websocket"
+)
+
+// wsEnvelope is the single message shape multiplexed over /api/ws. Payload
+// is left raw so each type can carry its own shape without the envelope
+// needing to know it.
+type wsEnvelope struct {
+    Type    string          `json:"type"`
+    ID      string          `json:"id,omitempty"`
+    Payload json.RawMessage `json:"payload,omitempty"`
+}
+
+type wsUserMessage struct {
+    Message   string `json:"message"`
+    SessionID string `json:"sessionId,omitempty"`
+}
+
+var wsUpgrader = websocket.Upgrader{
+    CheckOrigin: func(r *http.Request) bool {
+        origin := r.Header.Get("Origin")
+        return origin == "" || corsConfigFromEnv().originAllowed(origin)
+    },
+}
+
+// wsSession owns one upgraded connection. Incoming user_message envelopes
+// are queued and processed one at a time, so concurrent turns on the same
+// connection don't race each other's claude subprocess. cancel is called as
+// soon as the read loop detects the connection is gone, so an in-flight
+// claude subprocess gets killed instead of running to completion after the
+// client is gone — it does not wait on the queue draining first, since
+// draining is itself waiting on that subprocess to exit.
+type wsSession struct {
+    conn            *websocket.Conn
+    writeMu         sync.Mutex
+    queue           chan wsEnvelope
+    ctx             context.Context
+    cancel          context.CancelFunc
+    ip              string
+    rateLimiter     *RateLimiter
+    maxDailyCostUSD float64
+}
+
+// newWsHandler returns the /api/ws handler. protect() only gates the
+// handshake, so rl and maxDailyCostUSD are threaded through to wsSession as
+// well: otherwise one client that passes the handshake gate once could send
+// unlimited paid turns over the same connection, per-message checks in
+// handleUserMessage are what actually stop that.
+func newWsHandler(rl *RateLimiter, maxDailyCostUSD float64) http.HandlerFunc {
+    return func(w http.ResponseWriter, r *http.Request) {
+        conn, err := wsUpgrader.Upgrade(w, r, nil)
+        if err != nil {
+            log.Printf("ws upgrade failed: %v", err)
+            return
+        }
+        defer conn.Close()
+
+        ctx, cancel := context.WithCancel(r.Context())
+        defer cancel()
+
+        sess := &wsSession{
+            conn:            conn,
+            queue:           make(chan wsEnvelope, 16),
+            ctx:             ctx,
+            cancel:          cancel,
+            ip:              clientIP(r),
+            rateLimiter:     rl,
+            maxDailyCostUSD: maxDailyCostUSD,
+        }
+        sess.run()
+    }
+}
+
+func (s *wsSession) run() {
+    done := make(chan struct{})
+    go func() {
+        defer close(done)
+        for env := range s.queue {
+            s.handleUserMessage(env)
+        }
+    }()
+    defer func() {
+        close(s.queue)
+        <-done
+    }()
+
+    for {
+        var env wsEnvelope
+        if err := s.conn.ReadJSON(&env); err != nil {
+            // The connection is gone: cancel immediately so an in-flight
+            // claude subprocess is killed now, rather than after the queue
+            // drain below (which can't finish until that same subprocess
+            // exits on its own).
+            s.cancel()
+            return
+        }
+
+        switch env.Type {
+        case "user_message":
+            s.queue <- env
+        case "ping":
+            s.send(wsEnvelope{Type: "ping", ID: env.ID})
+        default:
+            s.send(wsEnvelope{Type: "error", ID: env.ID, Payload: wsErrorPayload("unknown message type: " + env.Type)})
+        }
+    }
+}
+
+func (s *wsSession) send(env wsEnvelope) {
+    s.writeMu.Lock()
+    defer s.writeMu.Unlock()
+    if err := s.conn.WriteJSON(env); err != nil {
+        log.Printf("ws write failed: %v", err)
+    }
+}
+
+func wsErrorPayload(msg string) json.RawMessage {
+    b, _ := json.Marshal(map[string]string{"error": msg})
+    return b
+}
+
+func (s *wsSession) handleUserMessage(env wsEnvelope) {
+    // Every turn spawns its own claude subprocess and spends real cost, so
+    // each one is re-checked against the rate limit and the daily cost
+    // budget, not just once at connect time. Proof of work is only checked
+    // on the handshake.
+    if !s.rateLimiter.allow(s.ip) {
+        s.send(wsEnvelope{Type: "error", ID: env.ID, Payload: wsErrorPayload("rate limit exceeded")})
+        return
+    }
+    if s.maxDailyCostUSD > 0 {
+        spent, err := totalCostToday(sessionStore)
+        if err == nil && spent >= s.maxDailyCostUSD {
+            s.send(wsEnvelope{Type: "error", ID: env.ID, Payload: wsErrorPayload("daily Claude cost budget exceeded")})
+            return
+        }
+    }
+
+    var msg wsUserMessage
+    if err := json.Unmarshal(env.Payload, &msg); err != nil {
+        s.send(wsEnvelope{Type: "error", ID: env.ID, Payload: wsErrorPayload(err.Error())})
+        return
+    }
+
+    args := []string{"-p", msg.Message, "--output-format", "stream-json"}
+    if msg.SessionID != "" {
+        args = append(args, "--resume", msg.SessionID)
+    }
+
+    // exec.CommandContext ties the subprocess to the websocket's lifetime,
+    // same as chatStreamHandler does for SSE: if the client disconnects
+    // mid-turn, s.ctx is cancelled and the child is killed instead of
+    // running to completion unobserved.
+    cmd := exec.CommandContext(s.ctx, "claude", args...)
+    stdout, err := cmd.StdoutPipe()
+    if err != nil {
+        s.send(wsEnvelope{Type: "error", ID: env.ID, Payload: wsErrorPayload(err.Error())})
+        return
+    }
+    if err := cmd.Start(); err != nil {
+        s.send(wsEnvelope{Type: "error", ID: env.ID, Payload: wsErrorPayload(err.Error())})
+        return
+    }
+
+    scanner := bufio.NewScanner(stdout)
+    scanner.Buffer(make([]byte, 0, 64*1024), 1024*1024)
+    for scanner.Scan() {
+        line := scanner.Bytes()
+        if len(line) == 0 {
+            continue
+        }
+
+        var streamEnv streamEnvelope
+        if err := json.Unmarshal(line, &streamEnv); err != nil {
+            continue
+        }
+
+        wsType := wsTypeForStreamEvent(streamEnv.Type)
+        s.send(wsEnvelope{Type: wsType, ID: env.ID, Payload: json.RawMessage(line)})
+
+        if wsType == "assistant_done" {
+            var claudeResp ClaudeResponse
+            if err := json.Unmarshal(line, &claudeResp); err == nil {
+                recordTurn(ChatRequest{Message: msg.Message, SessionID: msg.SessionID}, &claudeResp)
+            }
+        }
+    }
+
+    if err := cmd.Wait(); err != nil {
+        s.send(wsEnvelope{Type: "error", ID: env.ID, Payload: wsErrorPayload(err.Error())})
+    }
+}
+
+func wsTypeForStreamEvent(streamType string) string {
+    switch streamType {
+    case "tool_use":
+        return "tool_call"
+    case "tool_result":
+        return "tool_result"
+    case "result":
+        return "assistant_done"
+    default:
+        return "assistant_delta"
+    }
+}